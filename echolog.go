@@ -2,10 +2,12 @@ package applog
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"regexp"
 	"runtime"
 	"strings"
 	"sync"
@@ -25,23 +27,44 @@ const (
 	ERROR
 )
 
+// String returns the lowercase name of the level, as written to LogEntry.Level.
+func (l LogLevel) String() string {
+	switch l {
+	case DEBUG:
+		return "debug"
+	case INFO:
+		return "info"
+	case WARN:
+		return "warn"
+	case ERROR:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
 // LogEntry represents a structured log entry
 type LogEntry struct {
-	Timestamp   time.Time       `json:"timestamp"`
-	Level       string          `json:"level"`
-	RequestID   string          `json:"request_id"`
-	Method      string          `json:"method"`
-	URI         string          `json:"uri"`
-	Status      int             `json:"status"`
-	Latency     time.Duration   `json:"latency"`
-	ReqHeaders  json.RawMessage `json:"request_headers,omitempty"`
-	ReqBody     string          `json:"request_body,omitempty"`
-	RespHeaders json.RawMessage `json:"response_headers,omitempty"`
-	RespBody    string          `json:"response_body,omitempty"`
-	Error       string          `json:"error,omitempty"`
-	Stack       string          `json:"stack_trace,omitempty"`
-	ClientIP    string          `json:"client_ip"`
-	UserAgent   string          `json:"user_agent"`
+	Timestamp   time.Time              `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Message     string                 `json:"message,omitempty"`
+	RequestID   string                 `json:"request_id"`
+	Method      string                 `json:"method,omitempty"`
+	URI         string                 `json:"uri,omitempty"`
+	Status      int                    `json:"status,omitempty"`
+	Latency     time.Duration          `json:"latency,omitempty"`
+	ReqHeaders  json.RawMessage        `json:"request_headers,omitempty"`
+	ReqBody     string                 `json:"request_body,omitempty"`
+	RespHeaders json.RawMessage        `json:"response_headers,omitempty"`
+	RespBody    string                 `json:"response_body,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	Stack       string                 `json:"stack_trace,omitempty"`
+	ClientIP    string                 `json:"client_ip,omitempty"`
+	UserAgent   string                 `json:"user_agent,omitempty"`
+	Fields      map[string]interface{} `json:"fields,omitempty"`
+	TraceID     string                 `json:"trace_id,omitempty"`
+	SpanID      string                 `json:"span_id,omitempty"`
+	TraceFlags  string                 `json:"trace_flags,omitempty"`
 }
 
 // LoggerConfig provides configuration options for the logger middleware
@@ -54,21 +77,72 @@ type LoggerConfig struct {
 	Compress   bool // compress old files
 
 	// Logger behavior configuration
-	LogLevel          LogLevel
+	LogLevel          LogLevel // minimum level to record; see LevelOverrides
 	SkipPaths         []string
 	MaxBodySize       int64  // maximum size of body to log
 	RequestIDHeader   string // header to use for request ID
 	DisableRequestLog bool   // disable request body logging
 	DisableStackTrace bool   // disable stack trace for errors
 
+	// LevelOverrides sets a minimum LogLevel per path prefix, taking
+	// precedence over LogLevel for matching paths (longest prefix wins).
+	LevelOverrides map[string]LogLevel
+
+	// Sampler decides whether a request that passed the LogLevel/
+	// LevelOverrides check is actually written. Defaults to AlwaysSample.
+	Sampler Sampler
+
 	// Output options
 	JSONOutput bool // output logs in JSON format
+
+	// Format is a template string for text-mode output, using ${tag}
+	// placeholders (e.g. "${time_rfc3339} ${method} ${uri} ${status}").
+	// Ignored when JSONOutput is set. Defaults to DefaultFormat.
+	Format string
+
+	// Remote, if set, ships log entries to a remote HTTP collector in
+	// addition to the local lumberjack file. See RemoteSinkConfig.
+	Remote *RemoteSinkConfig
+
+	// Redaction configuration. RedactHeaders defaults to Authorization,
+	// Cookie, Set-Cookie, and X-Api-Key when left nil.
+	RedactHeaders     []string         // case-insensitive header names to redact
+	RedactQueryParams []string         // case-insensitive query param names to redact
+	RedactJSONFields  []string         // dotted JSON paths, e.g. "user.password"
+	RedactPatterns    []*regexp.Regexp // applied to raw request/response bodies
+	RedactPlaceholder string           // defaults to "***"
+
+	// ContentTypeAllowlist restricts body capture to matching Content-Type
+	// prefixes (e.g. "application/json", "text/"), on top of the existing
+	// multipart guard. Empty means no additional restriction.
+	ContentTypeAllowlist []string
+
+	// PropagateTraceParent generates a W3C traceparent (and echoes it back
+	// via the response header) when the incoming request carries neither
+	// an active OTel span nor a traceparent header of its own.
+	PropagateTraceParent bool
 }
 
 type Logger struct {
-	config LoggerConfig
-	lumber *lumberjack.Logger
-	mu     sync.Mutex
+	config   LoggerConfig
+	lumber   *lumberjack.Logger
+	remote   *RemoteSink
+	template *template
+	redactor *redactor
+
+	// mu is a pointer so that With/withEntry can copy the Logger (to
+	// attach per-call fields or bind it to a request) without duplicating
+	// the lock: every copy still serializes writes against the same
+	// underlying lumberjack.Logger.
+	mu *sync.Mutex
+
+	// fields carries over to every log call made through this Logger
+	// (set via With); reqEntry, when non-nil, is the in-flight request's
+	// LogEntry that Debug/Info/Warn/Error merge into rather than writing
+	// a standalone entry. Both are populated by With/withEntry, never on
+	// the root Logger returned by NewLogger.
+	fields   map[string]interface{}
+	reqEntry *LogEntry
 }
 
 // NewLogger creates a new logger instance with the given configuration
@@ -90,11 +164,44 @@ func NewLogger(config LoggerConfig) *Logger {
 			MaxAge:     config.MaxAge,
 			Compress:   config.Compress,
 		},
+		template: compileTemplate(config.Format),
+		redactor: newRedactor(config),
+		mu:       new(sync.Mutex),
+	}
+
+	if config.Remote != nil {
+		logger.remote = NewRemoteSink(*config.Remote)
 	}
 
 	return logger
 }
 
+// Start launches the logger's background workers, currently just the
+// remote sink (if configured). It is a no-op otherwise.
+func (l *Logger) Start(ctx context.Context) {
+	if l.remote != nil {
+		l.remote.Start(ctx)
+	}
+}
+
+// Shutdown stops the logger's background workers, flushing any pending
+// remote batches within the deadline on ctx.
+func (l *Logger) Shutdown(ctx context.Context) error {
+	if l.remote != nil {
+		return l.remote.Shutdown(ctx)
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the remote sink's counters. It returns the
+// zero value if no remote sink is configured.
+func (l *Logger) Stats() SinkStats {
+	if l.remote != nil {
+		return l.remote.Stats()
+	}
+	return SinkStats{}
+}
+
 // Middleware returns an Echo middleware handler
 func (l *Logger) Middleware() echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -108,16 +215,26 @@ func (l *Logger) Middleware() echo.MiddlewareFunc {
 			req := c.Request()
 			res := c.Response()
 
+			requestID := req.Header.Get(l.config.RequestIDHeader)
+			if requestID == "" {
+				requestID = generateRequestID()
+				res.Header().Set(l.config.RequestIDHeader, requestID)
+			}
+
 			// Create log entry
 			entry := &LogEntry{
 				Timestamp: start,
-				RequestID: req.Header.Get(l.config.RequestIDHeader),
+				RequestID: requestID,
 				Method:    req.Method,
-				URI:       req.RequestURI,
+				URI:       l.redactor.redactURI(req.RequestURI),
 				ClientIP:  c.RealIP(),
 				UserAgent: req.UserAgent(),
 			}
 
+			if traceParent := extractTraceContext(req, entry, l.config.PropagateTraceParent); traceParent != "" {
+				res.Header().Set("traceparent", traceParent)
+			}
+
 			// Log request headers and body
 			if !l.config.DisableRequestLog {
 				l.captureRequest(req, entry)
@@ -130,6 +247,10 @@ func (l *Logger) Middleware() echo.MiddlewareFunc {
 			}
 			res.Writer = resWriter
 
+			// Stash a request-scoped logger so handlers can add their own
+			// structured fields via FromContext; they land in entry.Fields.
+			c.Set(loggerContextKey, l.withEntry(entry))
+
 			// Process request
 			err := next(c)
 
@@ -144,11 +265,24 @@ func (l *Logger) Middleware() echo.MiddlewareFunc {
 				}
 			}
 
+			if entry.Level == "" {
+				entry.Level = deriveLevel(entry.Status, entry.Error).String()
+			}
+
+			// Drop anything below the configured level for this path, then
+			// consult the sampler for everything that's left.
+			if deriveLevel(entry.Status, entry.Error) < l.levelFor(req.URL.Path) {
+				return err
+			}
+			if l.config.Sampler != nil && !l.config.Sampler.Sample(entry) {
+				return err
+			}
+
 			// Log response headers and body
 			l.captureResponse(res, resWriter, entry)
 
 			// Write log entry
-			l.writeLog(entry)
+			l.writeLog(c, entry)
 
 			return err
 		}
@@ -168,15 +302,17 @@ func (l *Logger) shouldSkip(path string) bool {
 
 func (l *Logger) captureRequest(req *http.Request, entry *LogEntry) {
 	// Capture headers
-	if headers, err := json.Marshal(req.Header); err == nil {
+	if headers, err := json.Marshal(l.redactor.redactHeaders(req.Header)); err == nil {
 		entry.ReqHeaders = headers
 	}
 
-	// Capture body if not multipart
-	if req.Header.Get("Content-Type") != "multipart/form-data" {
+	contentType := req.Header.Get("Content-Type")
+
+	// Capture body if not multipart and allowed by ContentTypeAllowlist
+	if contentType != "multipart/form-data" && l.redactor.allowedContentType(contentType) {
 		body, err := io.ReadAll(io.LimitReader(req.Body, l.config.MaxBodySize))
 		if err == nil {
-			entry.ReqBody = string(body)
+			entry.ReqBody = string(l.redactor.redactBody(contentType, body))
 			req.Body = io.NopCloser(bytes.NewBuffer(body))
 		}
 	}
@@ -184,17 +320,23 @@ func (l *Logger) captureRequest(req *http.Request, entry *LogEntry) {
 
 func (l *Logger) captureResponse(res *echo.Response, rw *responseWriter, entry *LogEntry) {
 	// Capture headers
-	if headers, err := json.Marshal(res.Header()); err == nil {
+	if headers, err := json.Marshal(l.redactor.redactHeaders(res.Header())); err == nil {
 		entry.RespHeaders = headers
 	}
 
 	// Capture body
-	if rw.body.Len() > 0 {
-		entry.RespBody = rw.body.String()
+	if rw.body.Len() > 0 && l.redactor.allowedContentType(res.Header().Get("Content-Type")) {
+		entry.RespBody = string(l.redactor.redactBody(res.Header().Get("Content-Type"), rw.body.Bytes()))
 	}
 }
 
-func (l *Logger) writeLog(entry *LogEntry) {
+func (l *Logger) writeLog(c echo.Context, entry *LogEntry) {
+	if l.remote != nil {
+		if data, err := entryJSON(entry); err == nil {
+			l.remote.Enqueue(data)
+		}
+	}
+
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
@@ -203,15 +345,7 @@ func (l *Logger) writeLog(entry *LogEntry) {
 			l.lumber.Write(append(data, '\n'))
 		}
 	} else {
-		// Format as text
-		fmt.Fprintf(l.lumber, "[%s] %s %s %s %d %v\n",
-			entry.Timestamp.Format(time.RFC3339),
-			entry.RequestID,
-			entry.Method,
-			entry.URI,
-			entry.Status,
-			entry.Latency,
-		)
+		l.lumber.Write(l.template.Render(c, entry))
 		if entry.Error != "" {
 			fmt.Fprintf(l.lumber, "Error: %s\n", entry.Error)
 			if entry.Stack != "" {
@@ -0,0 +1,135 @@
+package applog
+
+import (
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// loggerContextKey is the echo.Context key the middleware stashes the
+// request-scoped Logger under, for retrieval via FromContext.
+const loggerContextKey = "applog_logger"
+
+// Field is a single structured key/value pair attached to a log call.
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// String builds a string-valued Field.
+func String(key, value string) Field { return Field{Key: key, Value: value} }
+
+// Int builds an int-valued Field.
+func Int(key string, value int) Field { return Field{Key: key, Value: value} }
+
+// Bool builds a bool-valued Field.
+func Bool(key string, value bool) Field { return Field{Key: key, Value: value} }
+
+// Any builds a Field from an arbitrary value.
+func Any(key string, value interface{}) Field { return Field{Key: key, Value: value} }
+
+// Err builds a Field named "error" from err. If err is nil the Field's
+// Value is nil, so the "error" key is still recorded, with a null value.
+func Err(err error) Field {
+	if err == nil {
+		return Field{Key: "error"}
+	}
+	return Field{Key: "error", Value: err.Error()}
+}
+
+// With returns a child Logger that carries fields on every subsequent
+// Debug/Info/Warn/Error call, in addition to whatever that call passes
+// directly. The receiver is left untouched.
+func (l *Logger) With(fields ...Field) *Logger {
+	if l == nil {
+		return nil
+	}
+	child := *l
+	child.fields = mergeFieldMap(l.fields, fields)
+	return &child
+}
+
+// Debug logs msg at DEBUG level with the given fields.
+func (l *Logger) Debug(msg string, fields ...Field) { l.log(DEBUG, msg, fields) }
+
+// Info logs msg at INFO level with the given fields.
+func (l *Logger) Info(msg string, fields ...Field) { l.log(INFO, msg, fields) }
+
+// Warn logs msg at WARN level with the given fields.
+func (l *Logger) Warn(msg string, fields ...Field) { l.log(WARN, msg, fields) }
+
+// Error logs msg at ERROR level with the given fields.
+func (l *Logger) Error(msg string, fields ...Field) { l.log(ERROR, msg, fields) }
+
+// FromContext returns the request-scoped Logger stashed by Middleware, or
+// nil if no logger is bound to c (e.g. Middleware isn't installed). All
+// logging methods are safe to call on a nil *Logger; they become no-ops.
+func FromContext(c echo.Context) *Logger {
+	if c == nil {
+		return nil
+	}
+	lg, _ := c.Get(loggerContextKey).(*Logger)
+	return lg
+}
+
+// withEntry returns a copy of l bound to entry: subsequent Debug/Info/Warn/
+// Error calls on the copy merge their fields into entry.Fields instead of
+// writing a standalone LogEntry.
+func (l *Logger) withEntry(entry *LogEntry) *Logger {
+	child := *l
+	child.reqEntry = entry
+	return &child
+}
+
+// log is the shared implementation behind Debug/Info/Warn/Error. When l is
+// bound to an in-flight request (via withEntry/FromContext), it merges
+// into that request's LogEntry; otherwise it writes a standalone entry
+// immediately, making Logger usable as a general application logger.
+func (l *Logger) log(level LogLevel, msg string, fields []Field) {
+	if l == nil {
+		return
+	}
+	if l.reqEntry != nil {
+		l.mergeIntoEntry(level, msg, fields)
+		return
+	}
+
+	entry := &LogEntry{
+		Timestamp: time.Now(),
+		Level:     level.String(),
+		Message:   msg,
+		Fields:    mergeFieldMap(l.fields, fields),
+	}
+	l.writeLog(nil, entry)
+}
+
+func (l *Logger) mergeIntoEntry(level LogLevel, msg string, fields []Field) {
+	entry := l.reqEntry
+	entry.Level = level.String()
+	if msg != "" {
+		entry.Message = msg
+	}
+	if entry.Fields == nil {
+		entry.Fields = make(map[string]interface{}, len(l.fields)+len(fields))
+	}
+	for k, v := range l.fields {
+		entry.Fields[k] = v
+	}
+	for _, f := range fields {
+		entry.Fields[f.Key] = f.Value
+	}
+}
+
+func mergeFieldMap(base map[string]interface{}, fields []Field) map[string]interface{} {
+	if len(base) == 0 && len(fields) == 0 {
+		return nil
+	}
+	merged := make(map[string]interface{}, len(base)+len(fields))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for _, f := range fields {
+		merged[f.Key] = f.Value
+	}
+	return merged
+}
@@ -0,0 +1,188 @@
+package applog
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DefaultFormat is used when LoggerConfig.Format is left empty. It mirrors
+// the line writeLog has always produced in text mode, plus ${message} so
+// standalone/handler log calls (which carry no HTTP metadata) still render
+// something readable instead of an empty-looking line.
+const DefaultFormat = "[${time_rfc3339}] ${id} ${method} ${uri} ${status} ${latency_human} ${message}\n"
+
+// tagFunc renders a single token into buf, given the current request's
+// echo.Context and the LogEntry the middleware built for it.
+type tagFunc func(buf *bytes.Buffer, c echo.Context, entry *LogEntry)
+
+// template is a Format string compiled once into an ordered list of
+// renderers, avoiding repeated parsing on every request.
+type template struct {
+	tags []tagFunc
+	pool sync.Pool
+}
+
+// compileTemplate parses a Format string such as
+// "${time_rfc3339} ${method} ${uri} ${header:X-Request-ID}" into a
+// template. Unknown tokens are rendered as empty strings, matching Echo's
+// own logger middleware behavior.
+func compileTemplate(format string) *template {
+	if format == "" {
+		format = DefaultFormat
+	}
+
+	t := &template{}
+	t.pool.New = func() interface{} { return new(bytes.Buffer) }
+
+	for {
+		start := strings.Index(format, "${")
+		if start == -1 {
+			if format != "" {
+				t.tags = append(t.tags, literalTag(format))
+			}
+			break
+		}
+		if start > 0 {
+			t.tags = append(t.tags, literalTag(format[:start]))
+		}
+
+		end := strings.Index(format[start:], "}")
+		if end == -1 {
+			t.tags = append(t.tags, literalTag(format[start:]))
+			break
+		}
+		end += start
+
+		token := format[start+2 : end]
+		t.tags = append(t.tags, resolveTag(token))
+		format = format[end+1:]
+	}
+
+	return t
+}
+
+// Render writes the rendered template for a single request into a pooled
+// buffer and returns a copy of its bytes, so the caller may keep the slice
+// while the buffer goes back to the pool.
+func (t *template) Render(c echo.Context, entry *LogEntry) []byte {
+	buf := t.pool.Get().(*bytes.Buffer)
+	buf.Reset()
+	defer t.pool.Put(buf)
+
+	for _, tag := range t.tags {
+		tag(buf, c, entry)
+	}
+
+	out := make([]byte, buf.Len())
+	copy(out, buf.Bytes())
+	return out
+}
+
+func literalTag(s string) tagFunc {
+	return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+		buf.WriteString(s)
+	}
+}
+
+// resolveTag maps a token name (and, for dynamic tokens, its argument) to a
+// tagFunc. Tokens are matched against the request/response visible on c as
+// well as the already-populated LogEntry.
+func resolveTag(token string) tagFunc {
+	if name, arg, ok := strings.Cut(token, ":"); ok {
+		switch name {
+		case "header":
+			return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+				if c != nil {
+					buf.WriteString(c.Request().Header.Get(arg))
+				}
+			}
+		case "query":
+			return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+				if c != nil {
+					buf.WriteString(c.QueryParam(arg))
+				}
+			}
+		case "form":
+			return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+				if c != nil {
+					buf.WriteString(c.FormValue(arg))
+				}
+			}
+		case "cookie":
+			return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+				if c == nil {
+					return
+				}
+				if cookie, err := c.Cookie(arg); err == nil {
+					buf.WriteString(cookie.Value)
+				}
+			}
+		}
+		return literalTag("")
+	}
+
+	switch token {
+	case "time_rfc3339":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.Timestamp.Format("2006-01-02T15:04:05Z07:00"))
+		}
+	case "time_unix_nano":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(strconv.FormatInt(entry.Timestamp.UnixNano(), 10))
+		}
+	case "id":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.RequestID)
+		}
+	case "remote_ip":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.ClientIP)
+		}
+	case "method":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.Method)
+		}
+	case "uri":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.URI)
+		}
+	case "status":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(strconv.Itoa(entry.Status))
+		}
+	case "latency":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(strconv.FormatInt(int64(entry.Latency), 10))
+		}
+	case "latency_human":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.Latency.String())
+		}
+	case "bytes_in":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			if c != nil {
+				buf.WriteString(c.Request().Header.Get("Content-Length"))
+			}
+		}
+	case "bytes_out":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			if c != nil {
+				buf.WriteString(strconv.FormatInt(c.Response().Size, 10))
+			}
+		}
+	case "error":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.Error)
+		}
+	case "message":
+		return func(buf *bytes.Buffer, c echo.Context, entry *LogEntry) {
+			buf.WriteString(entry.Message)
+		}
+	default:
+		return literalTag("")
+	}
+}
@@ -0,0 +1,184 @@
+package applog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// defaultRedactHeaders lists the headers redacted by default when
+// LoggerConfig.RedactHeaders is left unset.
+var defaultRedactHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "X-Api-Key"}
+
+// defaultRedactPlaceholder replaces redacted values when
+// LoggerConfig.RedactPlaceholder is left unset.
+const defaultRedactPlaceholder = "***"
+
+// redactor applies LoggerConfig's redaction and content-type allowlist
+// rules to captured request/response data. Rules left empty pass the
+// corresponding data through unchanged; the methods need a non-nil
+// *redactor, which newRedactor always returns.
+type redactor struct {
+	headers      map[string]struct{} // lowercased header names
+	queryParams  map[string]struct{} // lowercased query param names
+	jsonFields   []string            // dotted paths, e.g. "user.password"
+	patterns     []*regexp.Regexp
+	placeholder  string
+	contentTypes []string // allowlist prefixes; empty means allow all
+}
+
+// newRedactor builds a redactor from config, applying the documented
+// defaults for headers and placeholder when left unset.
+func newRedactor(config LoggerConfig) *redactor {
+	headerList := config.RedactHeaders
+	if headerList == nil {
+		headerList = defaultRedactHeaders
+	}
+
+	r := &redactor{
+		headers:      toLowerSet(headerList),
+		queryParams:  toLowerSet(config.RedactQueryParams),
+		jsonFields:   config.RedactJSONFields,
+		patterns:     config.RedactPatterns,
+		placeholder:  config.RedactPlaceholder,
+		contentTypes: config.ContentTypeAllowlist,
+	}
+	if r.placeholder == "" {
+		r.placeholder = defaultRedactPlaceholder
+	}
+	return r
+}
+
+func toLowerSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = struct{}{}
+	}
+	return set
+}
+
+// redactHeaders returns a copy of h with configured header values replaced
+// by the placeholder, or h itself when no header names are configured. h is
+// never mutated.
+func (r *redactor) redactHeaders(h http.Header) http.Header {
+	if len(r.headers) == 0 {
+		return h
+	}
+	clone := h.Clone()
+	for name := range clone {
+		if _, ok := r.headers[strings.ToLower(name)]; ok {
+			clone[name] = []string{r.placeholder}
+		}
+	}
+	return clone
+}
+
+// redactURI replaces configured query parameter values in uri with the
+// placeholder.
+func (r *redactor) redactURI(uri string) string {
+	if len(r.queryParams) == 0 {
+		return uri
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	q := u.Query()
+	redacted := false
+	for name := range q {
+		if _, ok := r.queryParams[strings.ToLower(name)]; ok {
+			q.Set(name, r.placeholder)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return uri
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// allowedContentType reports whether contentType is covered by
+// ContentTypeAllowlist. An empty allowlist allows everything.
+func (r *redactor) allowedContentType(contentType string) bool {
+	if len(r.contentTypes) == 0 {
+		return true
+	}
+	ct := strings.ToLower(contentType)
+	if idx := strings.Index(ct, ";"); idx != -1 {
+		ct = ct[:idx]
+	}
+	ct = strings.TrimSpace(ct)
+	for _, allowed := range r.contentTypes {
+		if strings.HasPrefix(ct, strings.ToLower(allowed)) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactBody applies RedactPatterns and, for JSON-ish content types,
+// RedactJSONFields to body.
+func (r *redactor) redactBody(contentType string, body []byte) []byte {
+	body = r.redactPatterns(body)
+	if len(r.jsonFields) > 0 && isJSONContentType(contentType) {
+		body = r.redactJSONBody(body)
+	}
+	return body
+}
+
+func (r *redactor) redactPatterns(body []byte) []byte {
+	for _, p := range r.patterns {
+		body = p.ReplaceAll(body, []byte(r.placeholder))
+	}
+	return body
+}
+
+// redactJSONBody walks-and-rewrites body via encoding/json so redacted
+// values stay valid JSON, rather than doing string substitution. Bodies
+// that don't parse as JSON are returned unchanged.
+func (r *redactor) redactJSONBody(body []byte) []byte {
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return body
+	}
+	for _, field := range r.jsonFields {
+		redactJSONPath(data, strings.Split(field, "."), r.placeholder)
+	}
+	out, err := json.Marshal(data)
+	if err != nil {
+		return body
+	}
+	return out
+}
+
+// redactJSONPath walks data along path (a dotted field path already split
+// on ".") and overwrites the leaf value with placeholder, if present.
+func redactJSONPath(data interface{}, path []string, placeholder string) {
+	if len(path) == 0 {
+		return
+	}
+	m, ok := data.(map[string]interface{})
+	if !ok {
+		return
+	}
+	key := path[0]
+	if len(path) == 1 {
+		if _, exists := m[key]; exists {
+			m[key] = placeholder
+		}
+		return
+	}
+	if next, ok := m[key]; ok {
+		redactJSONPath(next, path[1:], placeholder)
+	}
+}
+
+func isJSONContentType(contentType string) bool {
+	return strings.Contains(strings.ToLower(contentType), "json")
+}
@@ -0,0 +1,412 @@
+package applog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// RemoteSinkConfig configures shipping of log entries to a remote HTTP
+// collector, in addition to the local lumberjack file. Entries are always
+// written to the local file as well; the sink does not replace it.
+type RemoteSinkConfig struct {
+	// BaseURL is the collector endpoint that batches are POSTed to.
+	BaseURL string
+	// Headers are added to every outgoing request, e.g. for auth.
+	Headers map[string]string
+	// BearerToken, if set, is sent as an "Authorization: Bearer <token>" header.
+	BearerToken string
+	// ContentEncoding selects how the batch body is compressed before
+	// POSTing, and sets the matching Content-Encoding header. One of "",
+	// "gzip", or "zstd"; "" sends the batch uncompressed.
+	ContentEncoding string
+
+	// MaxBatchBytes bounds how large a single batch may grow before it is
+	// flushed. Defaults to 256KiB.
+	MaxBatchBytes int
+	// MaxBatchDelay bounds how long entries may sit queued before the batch
+	// is flushed regardless of size. Defaults to 2s.
+	MaxBatchDelay time.Duration
+	// QueueSize is the capacity of the in-memory ring buffer, in entries.
+	// Defaults to 4096.
+	QueueSize int
+
+	// BufferFilename, when set, enables spilling batches to a rotating
+	// on-disk buffer (reusing lumberjack) when the in-memory queue
+	// overflows or the remote endpoint is unreachable. The buffer is
+	// drained on the next successful send.
+	BufferFilename   string
+	BufferMaxSize    int // megabytes
+	BufferMaxBackups int
+	BufferMaxAge     int // days
+
+	// MinBackoff/MaxBackoff bound the exponential backoff applied between
+	// failed send attempts. Defaults are 500ms and 30s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	// MaxRetries bounds how many attempts sendWithRetry makes on a single
+	// batch before giving up and spilling it to disk, so a sustained
+	// outage can't stall the run goroutine indefinitely. Defaults to 5.
+	MaxRetries int
+
+	// HTTPClient is used to send batches. Defaults to a client with a 10s
+	// timeout.
+	HTTPClient *http.Client
+}
+
+// SinkStats is a snapshot of RemoteSink counters, safe to read concurrently.
+type SinkStats struct {
+	Dropped   uint64
+	Retried   uint64
+	BytesSent uint64
+}
+
+// RemoteSink batches LogEntry records and ships them to a remote HTTP
+// collector. Entries are enqueued into a bounded in-memory ring buffer; a
+// background goroutine coalesces them into batches and POSTs them as
+// newline-delimited JSON. On failure, batches are spilled to a rotating
+// on-disk buffer and retried with exponential backoff.
+type RemoteSink struct {
+	cfg RemoteSinkConfig
+
+	queue  chan []byte
+	disk   *lumberjack.Logger
+	diskMu sync.Mutex
+
+	dropped   uint64
+	retried   uint64
+	bytesSent uint64
+
+	wg   sync.WaitGroup
+	stop chan context.Context
+}
+
+// NewRemoteSink creates a RemoteSink from the given configuration, applying
+// defaults for any unset fields.
+func NewRemoteSink(cfg RemoteSinkConfig) *RemoteSink {
+	if cfg.MaxBatchBytes == 0 {
+		cfg.MaxBatchBytes = 256 * 1024
+	}
+	if cfg.MaxBatchDelay == 0 {
+		cfg.MaxBatchDelay = 2 * time.Second
+	}
+	if cfg.QueueSize == 0 {
+		cfg.QueueSize = 4096
+	}
+	if cfg.MinBackoff == 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff == 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	s := &RemoteSink{
+		cfg:   cfg,
+		queue: make(chan []byte, cfg.QueueSize),
+	}
+	if cfg.BufferFilename != "" {
+		s.disk = &lumberjack.Logger{
+			Filename:   cfg.BufferFilename,
+			MaxSize:    cfg.BufferMaxSize,
+			MaxBackups: cfg.BufferMaxBackups,
+			MaxAge:     cfg.BufferMaxAge,
+		}
+	}
+	return s
+}
+
+// Enqueue adds a marshaled LogEntry to the ring buffer. If the buffer is
+// full, the entry is spilled directly to the on-disk buffer (if configured)
+// or, without one, dropped and counted in Dropped.
+func (s *RemoteSink) Enqueue(data []byte) {
+	line := append(append([]byte(nil), data...), '\n')
+	select {
+	case s.queue <- line:
+	default:
+		if s.disk != nil {
+			s.diskMu.Lock()
+			s.disk.Write(line)
+			s.diskMu.Unlock()
+		} else {
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	}
+}
+
+// Start launches the background batching/shipping goroutine. It runs until
+// ctx is canceled or Shutdown is called.
+func (s *RemoteSink) Start(ctx context.Context) {
+	s.stop = make(chan context.Context)
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Shutdown stops the background goroutine and flushes any pending batch,
+// using ctx as the deadline for that final flush.
+func (s *RemoteSink) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case s.stop <- ctx:
+	case <-done:
+		return nil
+	}
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stats returns a snapshot of the sink's counters.
+func (s *RemoteSink) Stats() SinkStats {
+	return SinkStats{
+		Dropped:   atomic.LoadUint64(&s.dropped),
+		Retried:   atomic.LoadUint64(&s.retried),
+		BytesSent: atomic.LoadUint64(&s.bytesSent),
+	}
+}
+
+func (s *RemoteSink) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	timer := time.NewTimer(s.cfg.MaxBatchDelay)
+	defer timer.Stop()
+
+	var batch bytes.Buffer
+
+	flush := func(flushCtx context.Context) {
+		if batch.Len() > 0 {
+			data := append([]byte(nil), batch.Bytes()...)
+			batch.Reset()
+			if err := s.sendWithRetry(flushCtx, data); err != nil {
+				s.spillToDisk(data)
+			}
+		}
+		s.drainDisk(flushCtx)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			flush(ctx)
+			return
+		case shutdownCtx := <-s.stop:
+			flush(shutdownCtx)
+			return
+		case line := <-s.queue:
+			batch.Write(line)
+			if batch.Len() >= s.cfg.MaxBatchBytes {
+				flush(ctx)
+				timer.Reset(s.cfg.MaxBatchDelay)
+			}
+		case <-timer.C:
+			flush(ctx)
+			timer.Reset(s.cfg.MaxBatchDelay)
+		}
+	}
+}
+
+// spillToDisk writes a batch that could not be delivered to the rotating
+// on-disk buffer, or drops it and counts it if no buffer is configured.
+func (s *RemoteSink) spillToDisk(data []byte) {
+	if s.disk == nil {
+		atomic.AddUint64(&s.dropped, 1)
+		return
+	}
+	s.diskMu.Lock()
+	s.disk.Write(data)
+	s.diskMu.Unlock()
+}
+
+// drainDisk replays any batches spilled to the on-disk buffer, including
+// any rotated backups lumberjack created while the buffer grew past
+// BufferMaxSize. It atomically takes the buffer (read + truncate under
+// diskMu) before sending, so entries Enqueue spills while the send is in
+// flight land in a fresh file instead of being silently rotated away.
+// A failed send respills the taken data rather than losing it.
+func (s *RemoteSink) drainDisk(ctx context.Context) {
+	if s.disk == nil {
+		return
+	}
+	data := s.takeDiskBuffer()
+	if len(data) == 0 {
+		return
+	}
+	if err := s.sendWithRetry(ctx, data); err != nil {
+		s.spillToDisk(data)
+	}
+}
+
+// takeDiskBuffer reads and removes every byte currently held in the disk
+// buffer (the active file plus any rotated backups) under diskMu, so
+// nothing written concurrently via Enqueue's overflow path is lost or
+// orphaned in a backup that never gets read again.
+func (s *RemoteSink) takeDiskBuffer() []byte {
+	s.diskMu.Lock()
+	defer s.diskMu.Unlock()
+
+	var buf bytes.Buffer
+
+	backups, _ := filepath.Glob(diskBufferBackupGlob(s.cfg.BufferFilename))
+	sort.Strings(backups)
+	for _, name := range backups {
+		if data, err := os.ReadFile(name); err == nil {
+			buf.Write(data)
+		}
+		os.Remove(name)
+	}
+
+	if data, err := os.ReadFile(s.cfg.BufferFilename); err == nil {
+		buf.Write(data)
+	}
+
+	// Close the lumberjack handle and remove the file out from under it;
+	// lumberjack lazily reopens/creates the file on its next Write.
+	s.disk.Close()
+	os.Remove(s.cfg.BufferFilename)
+
+	return buf.Bytes()
+}
+
+// diskBufferBackupGlob matches the backup files lumberjack creates next to
+// filename when rotating (e.g. "buffer-2024-01-02T15-04-05.000.jsonl").
+func diskBufferBackupGlob(filename string) string {
+	ext := filepath.Ext(filename)
+	base := strings.TrimSuffix(filepath.Base(filename), ext)
+	return filepath.Join(filepath.Dir(filename), base+"-*"+ext)
+}
+
+// sendWithRetry attempts delivery, retrying with exponential backoff and
+// jitter until it succeeds, ctx is done, or MaxRetries attempts have been
+// made. Bounding attempts keeps a sustained outage from blocking the run
+// goroutine (and therefore batch coalescing) indefinitely; the caller
+// spills to disk on error and tries again next tick.
+func (s *RemoteSink) sendWithRetry(ctx context.Context, data []byte) error {
+	backoff := s.cfg.MinBackoff
+	var lastErr error
+	for attempt := 0; attempt < s.cfg.MaxRetries; attempt++ {
+		err := s.send(ctx, data)
+		if err == nil {
+			atomic.AddUint64(&s.bytesSent, uint64(len(data)))
+			return nil
+		}
+		lastErr = err
+		if attempt > 0 {
+			atomic.AddUint64(&s.retried, 1)
+		}
+
+		jitter := time.Duration(rand.Int63n(int64(backoff) + 1))
+		select {
+		case <-time.After(backoff + jitter):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+		if backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+	return fmt.Errorf("remote sink: gave up after %d attempts: %w", s.cfg.MaxRetries, lastErr)
+}
+
+func (s *RemoteSink) send(ctx context.Context, data []byte) error {
+	body, err := compress(s.cfg.ContentEncoding, data)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.cfg.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", s.cfg.ContentEncoding)
+	}
+	if s.cfg.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.BearerToken)
+	}
+	for k, v := range s.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("remote sink: collector returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// compress encodes data per encoding ("", "gzip", or "zstd"), returning it
+// unchanged for "".
+func compress(encoding string, data []byte) ([]byte, error) {
+	switch encoding {
+	case "":
+		return data, nil
+	case "gzip":
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		if _, err := gw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := gw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case "zstd":
+		var buf bytes.Buffer
+		zw, err := zstd.NewWriter(&buf)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := zw.Write(data); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("remote sink: unsupported content encoding %q", encoding)
+	}
+}
+
+// entryJSON marshals a LogEntry for enqueueing onto a RemoteSink.
+func entryJSON(entry *LogEntry) ([]byte, error) {
+	return json.Marshal(entry)
+}
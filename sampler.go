@@ -0,0 +1,127 @@
+package applog
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Sampler decides whether a given request's LogEntry should be written.
+// It is consulted after the entry's HTTP metadata (status, latency, error)
+// has been filled in, so implementations can sample on those fields.
+type Sampler interface {
+	Sample(entry *LogEntry) bool
+}
+
+type samplerFunc func(entry *LogEntry) bool
+
+func (f samplerFunc) Sample(entry *LogEntry) bool { return f(entry) }
+
+// NeverSample drops every entry.
+var NeverSample Sampler = samplerFunc(func(*LogEntry) bool { return false })
+
+// AlwaysSample keeps every entry. It is the implicit behavior when
+// LoggerConfig.Sampler is left unset.
+var AlwaysSample Sampler = samplerFunc(func(*LogEntry) bool { return true })
+
+// RateSampler returns a Sampler that keeps 1 out of every n entries it
+// sees, counted across all requests the Logger handles (not per route).
+// A non-positive n keeps nothing.
+func RateSampler(n int) Sampler {
+	return &rateSampler{n: int64(n)}
+}
+
+type rateSampler struct {
+	n       int64
+	counter int64
+}
+
+func (r *rateSampler) Sample(*LogEntry) bool {
+	if r.n <= 0 {
+		return false
+	}
+	c := atomic.AddInt64(&r.counter, 1)
+	return c%r.n == 0
+}
+
+// BurstSampler is a token-bucket Sampler: it keeps up to Burst entries
+// instantly, then refills at PerSecond tokens per second.
+type BurstSampler struct {
+	PerSecond int
+	Burst     int
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func (b *BurstSampler) Sample(entry *LogEntry) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.last.IsZero() {
+		b.last = entry.Timestamp
+		b.tokens = float64(b.Burst)
+	} else if elapsed := entry.Timestamp.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens += elapsed * float64(b.PerSecond)
+		if b.tokens > float64(b.Burst) {
+			b.tokens = float64(b.Burst)
+		}
+		b.last = entry.Timestamp
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// AdaptiveSampler always keeps error responses (status >= 500 or a non-nil
+// handler error) and requests slower than LogSlowerThan, and delegates
+// everything else to Base (AlwaysSample if Base is nil).
+type AdaptiveSampler struct {
+	LogSlowerThan time.Duration
+	Base          Sampler
+}
+
+func (a *AdaptiveSampler) Sample(entry *LogEntry) bool {
+	if entry.Status >= 500 || entry.Error != "" {
+		return true
+	}
+	if a.LogSlowerThan > 0 && entry.Latency >= a.LogSlowerThan {
+		return true
+	}
+	if a.Base == nil {
+		return true
+	}
+	return a.Base.Sample(entry)
+}
+
+// deriveLevel classifies a completed request into a LogLevel based on its
+// status and whether the handler returned an error.
+func deriveLevel(status int, errMsg string) LogLevel {
+	if status >= 500 || errMsg != "" {
+		return ERROR
+	}
+	if status >= 400 {
+		return WARN
+	}
+	return INFO
+}
+
+// levelFor returns the minimum LogLevel to record for path, honoring
+// LevelOverrides (matched by longest matching prefix) and falling back to
+// the Logger's configured LogLevel.
+func (l *Logger) levelFor(path string) LogLevel {
+	level := l.config.LogLevel
+	bestLen := -1
+	for prefix, override := range l.config.LevelOverrides {
+		if len(prefix) > bestLen && strings.HasPrefix(path, prefix) {
+			level = override
+			bestLen = len(prefix)
+		}
+	}
+	return level
+}
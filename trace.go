@@ -0,0 +1,146 @@
+package applog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// traceParentVersion is the only W3C Trace Context version this package
+// understands, per https://www.w3.org/TR/trace-context/#version.
+const traceParentVersion = "00"
+
+// crockford is the Crockford base32 alphabet used for sortable request IDs.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// extractTraceContext fills entry's trace fields from (in priority order) an
+// active OTel span on req's context, or an incoming W3C traceparent header.
+// When neither is present and propagate is true, it mints a new trace/span
+// pair and returns the traceparent header value to echo back on the
+// response; otherwise it returns "".
+func extractTraceContext(req *http.Request, entry *LogEntry, propagate bool) string {
+	if sc := trace.SpanContextFromContext(req.Context()); sc.IsValid() {
+		entry.TraceID = sc.TraceID().String()
+		entry.SpanID = sc.SpanID().String()
+		entry.TraceFlags = sc.TraceFlags().String()
+		return ""
+	}
+
+	if header := req.Header.Get("traceparent"); header != "" {
+		if traceID, spanID, flags, ok := parseTraceParent(header); ok {
+			entry.TraceID = traceID
+			entry.SpanID = spanID
+			entry.TraceFlags = flags
+			return ""
+		}
+	}
+
+	if !propagate {
+		return ""
+	}
+
+	traceID, spanID, ok := mintIDs()
+	if !ok {
+		// crypto/rand failed us; don't mint an all-zero (W3C-invalid)
+		// trace context, just skip propagation.
+		return ""
+	}
+	entry.TraceID = traceID
+	entry.SpanID = spanID
+	entry.TraceFlags = "01"
+	return strings.Join([]string{traceParentVersion, traceID, spanID, "01"}, "-")
+}
+
+// parseTraceParent parses a W3C traceparent header value of the form
+// "version-traceid-spanid-flags", rejecting the all-zero trace-id/span-id
+// that the W3C spec marks invalid.
+func parseTraceParent(header string) (traceID, spanID, flags string, ok bool) {
+	parts := strings.Split(header, "-")
+	if len(parts) != 4 {
+		return "", "", "", false
+	}
+	version, id, span, flagsPart := parts[0], parts[1], parts[2], parts[3]
+	if version != traceParentVersion || len(id) != 32 || len(span) != 16 || len(flagsPart) != 2 {
+		return "", "", "", false
+	}
+	if isAllZeroHex(id) || isAllZeroHex(span) {
+		return "", "", "", false
+	}
+	return id, span, flagsPart, true
+}
+
+// isAllZeroHex reports whether s consists entirely of '0' characters, the
+// pattern W3C Trace Context reserves to mean "no trace-id"/"no span-id".
+func isAllZeroHex(s string) bool {
+	for _, c := range s {
+		if c != '0' {
+			return false
+		}
+	}
+	return true
+}
+
+// mintIDs generates a fresh (traceID, spanID) pair. ok is false if
+// crypto/rand failed or, vanishingly unlikely, produced an all-zero ID.
+func mintIDs() (traceID, spanID string, ok bool) {
+	traceID, ok1 := randomHex(16)
+	spanID, ok2 := randomHex(8)
+	if !ok1 || !ok2 || isAllZeroHex(traceID) || isAllZeroHex(spanID) {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+// randomHex returns n random bytes hex-encoded, or ok == false if
+// crypto/rand could not be read.
+func randomHex(n int) (hexStr string, ok bool) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false
+	}
+	return hex.EncodeToString(buf), true
+}
+
+// generateRequestID returns a sortable, ULID-style request ID: a 48-bit
+// millisecond timestamp followed by 80 bits of randomness, Crockford
+// base32-encoded.
+func generateRequestID() string {
+	var data [16]byte
+	ms := uint64(time.Now().UnixMilli())
+	data[0] = byte(ms >> 40)
+	data[1] = byte(ms >> 32)
+	data[2] = byte(ms >> 24)
+	data[3] = byte(ms >> 16)
+	data[4] = byte(ms >> 8)
+	data[5] = byte(ms)
+	if _, err := rand.Read(data[6:]); err != nil {
+		return hex.EncodeToString(data[:])
+	}
+	return encodeCrockford(data[:])
+}
+
+// encodeCrockford encodes data (expected to be 16 bytes, as produced by
+// generateRequestID) using the Crockford base32 alphabet, 5 bits at a time.
+func encodeCrockford(data []byte) string {
+	var bits uint
+	var value uint32
+	var out strings.Builder
+	out.Grow((len(data)*8 + 4) / 5)
+
+	for _, b := range data {
+		value = (value << 8) | uint32(b)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			out.WriteByte(crockford[(value>>bits)&0x1F])
+		}
+	}
+	if bits > 0 {
+		out.WriteByte(crockford[(value<<(5-bits))&0x1F])
+	}
+	return out.String()
+}